@@ -2,6 +2,11 @@ package discordgo
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	nats "github.com/nats-io/nats.go"
 )
@@ -66,11 +71,15 @@ func registerInterfaceProvider(eh EventInterfaceProvider) {
 // cannot be compared directly.
 type eventHandlerInstance struct {
 	eventHandler EventHandler
+
+	// synchronous forces this handler to run inline in handle(), in
+	// registration order, regardless of Session.SyncEvents.
+	synchronous bool
 }
 
 // addEventHandler adds an event handler that will be fired anytime
 // the Discord WSAPI matching eventHandler.Type() fires.
-func (s *Session) addEventHandler(eventHandler EventHandler) func() {
+func (s *Session) addEventHandler(eventHandler EventHandler, synchronous bool) func() {
 	s.handlersMu.Lock()
 	defer s.handlersMu.Unlock()
 
@@ -78,7 +87,7 @@ func (s *Session) addEventHandler(eventHandler EventHandler) func() {
 		s.handlers = map[string][]*eventHandlerInstance{}
 	}
 
-	ehi := &eventHandlerInstance{eventHandler}
+	ehi := &eventHandlerInstance{eventHandler, synchronous}
 	s.handlers[eventHandler.Type()] = append(s.handlers[eventHandler.Type()], ehi)
 
 	return func() {
@@ -88,7 +97,7 @@ func (s *Session) addEventHandler(eventHandler EventHandler) func() {
 
 // addEventHandler adds an event handler that will be fired the next time
 // the Discord WSAPI matching eventHandler.Type() fires.
-func (s *Session) addEventHandlerOnce(eventHandler EventHandler) func() {
+func (s *Session) addEventHandlerOnce(eventHandler EventHandler, synchronous bool) func() {
 	s.handlersMu.Lock()
 	defer s.handlersMu.Unlock()
 
@@ -96,7 +105,7 @@ func (s *Session) addEventHandlerOnce(eventHandler EventHandler) func() {
 		s.onceHandlers = map[string][]*eventHandlerInstance{}
 	}
 
-	ehi := &eventHandlerInstance{eventHandler}
+	ehi := &eventHandlerInstance{eventHandler, synchronous}
 	s.onceHandlers[eventHandler.Type()] = append(s.onceHandlers[eventHandler.Type()], ehi)
 
 	return func() {
@@ -149,7 +158,7 @@ func (s *Session) AddHandler(handler interface{}) func() {
 		}
 	}
 
-	return s.addEventHandler(eh)
+	return s.addEventHandler(eh, false)
 }
 
 // AddHandlerOnce allows you to add an event handler that will be fired the next time
@@ -163,7 +172,41 @@ func (s *Session) AddHandlerOnce(handler interface{}) func() {
 		return func() {}
 	}
 
-	return s.addEventHandlerOnce(eh)
+	return s.addEventHandlerOnce(eh, false)
+}
+
+// AddSyncHandler allows you to add an event handler that always runs
+// synchronously and inline, in registration order, regardless of
+// Session.SyncEvents. Use this for state-mutating or order-sensitive
+// handlers (a custom state layer, rate-limit bookkeeping) without forcing
+// every other handler on the session to pay for synchronous dispatch too.
+// It is safe for a synchronous handler to call the remove func returned
+// here for itself from inside Handle (e.g. "run N times then unsubscribe"):
+// handle() releases s.handlersMu before invoking any handler. See
+// AddHandler for more details.
+func (s *Session) AddSyncHandler(handler interface{}) func() {
+	eh := handlerForInterface(handler)
+
+	if eh == nil {
+		s.log(LogError, "Invalid handler type, handler will never be called")
+		return func() {}
+	}
+
+	return s.addEventHandler(eh, true)
+}
+
+// AddSyncHandlerOnce allows you to add an event handler that will be fired
+// the next time the Discord WSAPI event that matches the function fires,
+// always synchronously. See AddSyncHandler for more details.
+func (s *Session) AddSyncHandlerOnce(handler interface{}) func() {
+	eh := handlerForInterface(handler)
+
+	if eh == nil {
+		s.log(LogError, "Invalid handler type, handler will never be called")
+		return func() {}
+	}
+
+	return s.addEventHandlerOnce(eh, true)
 }
 
 // removeEventHandler instance removes an event handler instance.
@@ -175,56 +218,220 @@ func (s *Session) removeEventHandlerInstance(t string, ehi *eventHandlerInstance
 	for i := range handlers {
 		if handlers[i] == ehi {
 			s.handlers[t] = append(handlers[:i], handlers[i+1:]...)
+			break
 		}
 	}
 
 	onceHandlers := s.onceHandlers[t]
 	for i := range onceHandlers {
 		if onceHandlers[i] == ehi {
-			s.onceHandlers[t] = append(onceHandlers[:i], handlers[i+1:]...)
+			s.onceHandlers[t] = append(onceHandlers[:i], onceHandlers[i+1:]...)
+			break
 		}
 	}
 }
 
 // Handles calling permanent and once handlers for an event type.
+//
+// handle snapshots the handler slices for t under s.handlersMu, then
+// releases the lock before invoking anything. This is what lets a
+// synchronous handler safely call the remove func returned by its own
+// AddHandler/AddSyncHandler/... call from inside Handle: removal needs
+// s.handlersMu's write lock, and by the time Handle runs here that lock is
+// no longer held by this goroutine. Holding the lock across the
+// invocations instead would deadlock a goroutine that tries to re-acquire
+// it to remove itself. The write lock is taken because the once-handlers
+// for t are cleared in the same step.
 func (s *Session) handle(t string, i interface{}) {
-	for _, eh := range s.handlers[t] {
-		if s.SyncEvents {
+	s.handlersMu.Lock()
+	handlers := append([]*eventHandlerInstance(nil), s.handlers[t]...)
+	onceHandlers := s.onceHandlers[t]
+	if len(onceHandlers) > 0 {
+		s.onceHandlers[t] = nil
+	}
+	s.handlersMu.Unlock()
+
+	for _, eh := range handlers {
+		if eh.synchronous || s.SyncEvents {
 			eh.eventHandler.Handle(s, i)
 		} else {
 			go eh.eventHandler.Handle(s, i)
 		}
 	}
 
-	if len(s.onceHandlers[t]) > 0 {
-		for _, eh := range s.onceHandlers[t] {
-			if s.SyncEvents {
-				eh.eventHandler.Handle(s, i)
-			} else {
-				go eh.eventHandler.Handle(s, i)
-			}
+	for _, eh := range onceHandlers {
+		if eh.synchronous || s.SyncEvents {
+			eh.eventHandler.Handle(s, i)
+		} else {
+			go eh.eventHandler.Handle(s, i)
 		}
-		s.onceHandlers[t] = nil
 	}
 }
 
+// eventTypeFromNatsSubject recovers the bare event type (as registered with
+// registerInterfaceProvider) from a NATS subject. NatsMode 1 subscribes
+// under the bare type already, so this is a no-op for it; NatsMode 2
+// publishes under "<prefix>.<shard>.<type>", so this strips everything up
+// to and including the last ".".
+func eventTypeFromNatsSubject(subject string) string {
+	if idx := strings.LastIndex(subject, "."); idx != -1 {
+		return subject[idx+1:]
+	}
+	return subject
+}
+
 // Handles events coming in from NATS
 func (s *Session) natsHandler(m *nats.Msg) {
-	if eh, ok := registeredInterfaceProviders[m.Subject]; ok {
+	t := eventTypeFromNatsSubject(m.Subject)
+	if eh, ok := registeredInterfaceProviders[t]; ok {
 		i := eh.New()
 		// Attempt to unmarshal our event.
 		if err := json.Unmarshal(m.Data, i); err != nil {
-			s.log(LogError, "error unmarshalling %s event, %s", m.Subject, err)
+			s.log(LogError, "error unmarshalling %s event, %s", t, err)
 		}
-		s.handleEvent(m.Subject, i)
+		s.handleEvent(t, i)
+	}
+}
+
+// Handler is a small, self-contained event dispatcher with the same
+// registration semantics as Session.AddHandler. Session.PreHandler is an
+// instance of this type.
+type Handler struct {
+	handlersMu sync.RWMutex
+	handlers   map[string][]*eventHandlerInstance
+}
+
+// AddHandler registers a handler on this dispatcher. It accepts the same
+// function signatures as Session.AddHandler.
+func (h *Handler) AddHandler(handler interface{}) func() {
+	eh := handlerForInterface(handler)
+	if eh == nil {
+		return func() {}
+	}
+
+	h.handlersMu.Lock()
+	defer h.handlersMu.Unlock()
+
+	if h.handlers == nil {
+		h.handlers = map[string][]*eventHandlerInstance{}
+	}
+
+	ehi := &eventHandlerInstance{eh, true}
+	h.handlers[eh.Type()] = append(h.handlers[eh.Type()], ehi)
+
+	return func() {
+		h.handlersMu.Lock()
+		defer h.handlersMu.Unlock()
+
+		handlers := h.handlers[eh.Type()]
+		for i := range handlers {
+			if handlers[i] == ehi {
+				h.handlers[eh.Type()] = append(handlers[:i], handlers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// dispatch synchronously invokes the registered interface{} and typed
+// handlers for t, in that order. Like Session.handle, it snapshots the
+// handler slices under h.handlersMu and releases the lock before invoking
+// anything, so a pre-handler can safely call its own AddHandler-returned
+// remove func from inside Handle without deadlocking against h.handlersMu.
+func (h *Handler) dispatch(s *Session, t string, i interface{}) {
+	h.handlersMu.RLock()
+	ifaceHandlers := append([]*eventHandlerInstance(nil), h.handlers[interfaceEventType]...)
+	typedHandlers := append([]*eventHandlerInstance(nil), h.handlers[t]...)
+	h.handlersMu.RUnlock()
+
+	for _, eh := range ifaceHandlers {
+		eh.eventHandler.Handle(s, i)
+	}
+	for _, eh := range typedHandlers {
+		eh.eventHandler.Handle(s, i)
+	}
+}
+
+// AddPreHandler registers a pre-handler on s.PreHandler, lazily creating it
+// if necessary, and returns a function that removes it. See Session.PreHandler
+// for what pre-handlers are for and when they run.
+func (s *Session) AddPreHandler(handler interface{}) func() {
+	s.handlersMu.Lock()
+	if s.PreHandler == nil {
+		s.PreHandler = &Handler{}
+	}
+	ph := s.PreHandler
+	s.handlersMu.Unlock()
+
+	return ph.AddHandler(handler)
+}
+
+// DispatchDirect dispatches i to the handlers registered for event type t,
+// skipping the interfaceEventType fan-out that handleEvent performs. Any
+// handler registered the normal way, with AddHandler or AddHandlerOnce,
+// already works here — dispatch only ever reads the bucket for t, which is
+// the same bucket handleEvent uses, so no separate registration call is
+// needed. Use this for typed handlers on high-volume events that shouldn't
+// have to race a catch-all interface{} subscriber (e.g. a NATS republisher)
+// through the generic dispatch path.
+func (s *Session) DispatchDirect(t string, i interface{}) {
+	s.handle(t, i)
+}
+
+// HandlersForType returns a snapshot of the permanent EventHandlers
+// currently registered for event type t; once-handlers are not included.
+// Useful for command routers and other
+// libraries layered on top of discordgo that want to inspect the existing
+// handler registry instead of maintaining their own.
+func (s *Session) HandlersForType(t string) []EventHandler {
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+
+	handlers := s.handlers[t]
+	out := make([]EventHandler, 0, len(handlers))
+	for _, eh := range handlers {
+		out = append(out, eh.eventHandler)
 	}
+	return out
+}
+
+// HasHandler reports whether any permanent handler is currently registered
+// for event type t; once-handlers are not counted.
+func (s *Session) HasHandler(t string) bool {
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+
+	return len(s.handlers[t]) > 0
+}
+
+// CallHandlersFor dispatches i to the handlers registered for event type t,
+// without going through handleEvent: it does not mutate Session.State and
+// does not fan out to interface{} subscribers. It is equivalent to
+// DispatchDirect, exposed under this name for replay tooling, tests, and
+// command routers that want to invoke a handler chain as an explicit
+// primitive rather than as part of the main dispatch loop.
+func (s *Session) CallHandlersFor(t string, i interface{}) {
+	s.DispatchDirect(t, i)
 }
 
 // Handles an event type by calling internal methods, firing handlers and firing the
 // interface{} event.
+//
+// Neither this function nor the handle()/Handler.dispatch() calls it makes
+// hold s.handlersMu (or Handler.handlersMu) while a handler's Handle runs:
+// each only holds the lock long enough to snapshot the relevant handler
+// slice (handle() also clears the once-handlers for the type), then
+// releases it before invoking anything. That's what
+// lets a handler safely call the remove func returned by its own
+// registration from inside Handle without deadlocking against
+// AddHandler/RemoveHandler/AddPreHandler, which need the write lock.
 func (s *Session) handleEvent(t string, i interface{}) {
-	s.handlersMu.RLock()
-	defer s.handlersMu.RUnlock()
+	// Pre-handlers run synchronously before s.State mutates, so they can
+	// observe things like the old nickname on a GuildMemberUpdate or the
+	// channel that's about to be deleted. They must not block.
+	if s.PreHandler != nil {
+		s.PreHandler.dispatch(s, t, i)
+	}
 
 	if s.State != nil {
 		// All events are dispatched internally first.
@@ -236,6 +443,161 @@ func (s *Session) handleEvent(t string, i interface{}) {
 
 	// Finally they are dispatched to any typed handlers.
 	s.handle(t, i)
+
+	// NatsMode == 2 also republishes the event onto NATS, after the
+	// in-process handlers have been dispatched, so one set of gateway-connected
+	// shards can feed many stateless worker processes.
+	if s.NATS != nil && s.NatsMode == 2 {
+		s.natsPublish(t, i)
+	}
+}
+
+// natsSubjectPrefix returns the configured NATS subject prefix, defaulting
+// to "discord" when Session.NatsSubjectPrefix is unset.
+func (s *Session) natsSubjectPrefix() string {
+	if s.NatsSubjectPrefix == "" {
+		return "discord"
+	}
+	return s.NatsSubjectPrefix
+}
+
+// natsOutboundEvent is one event queued up for natsPublishLoop to publish.
+type natsOutboundEvent struct {
+	t string
+	i interface{}
+
+	// seq is the gateway sequence number at the time the event was
+	// dispatched, or nil if the session has no sequence counter.
+	seq *int64
+}
+
+// natsPublishQueueSize bounds how many outbound events can be buffered
+// waiting on a slow NATS/JetStream publish before natsPublish starts
+// dropping them rather than blocking its caller.
+const natsPublishQueueSize = 256
+
+// natsPublish hands the event off to a background goroutine that publishes
+// it to NATS, so publishing never happens inline on the dispatch path: a
+// slow network round trip (especially a JetStream publish waiting on an
+// ack) would otherwise stall handleEvent and delay every subsequent event.
+func (s *Session) natsPublish(t string, i interface{}) {
+	s.natsMu.Lock()
+	if s.natsPublishQueue == nil {
+		s.natsPublishQueue = make(chan natsOutboundEvent, natsPublishQueueSize)
+		go s.natsPublishLoop(s.natsPublishQueue)
+	}
+	queue := s.natsPublishQueue
+	s.natsMu.Unlock()
+
+	evt := natsOutboundEvent{t: t, i: i}
+	if s.sequence != nil {
+		seq := atomic.LoadInt64(s.sequence)
+		evt.seq = &seq
+	}
+
+	select {
+	case queue <- evt:
+	default:
+		s.log(LogWarning, "dropping %s event, NATS publish queue is full", t)
+	}
+}
+
+// natsPublishLoop drains queue, publishing each event in turn.
+func (s *Session) natsPublishLoop(queue chan natsOutboundEvent) {
+	for evt := range queue {
+		s.natsPublishSync(evt.t, evt.i, evt.seq)
+	}
+}
+
+// natsPublishSync marshals i and publishes it to NATS under
+// "<prefix>.<shard>.<event>", e.g. "discord.0.MESSAGE_CREATE". If
+// s.NatsJetStream is configured, it publishes through JetStream instead,
+// tagging the message with a nats.MsgId derived from the session ID and
+// gateway sequence number so replays of the same event are deduplicated.
+// The session ID keeps the MsgId unique across reconnects, since the
+// sequence counter itself resets on a non-resumed reconnect. seq is the
+// sequence number captured when the event was dispatched; nil omits the
+// MsgId.
+func (s *Session) natsPublishSync(t string, i interface{}, seq *int64) {
+	data, err := json.Marshal(i)
+	if err != nil {
+		s.log(LogError, "error marshalling %s event for NATS, %s", t, err)
+		return
+	}
+
+	subject := fmt.Sprintf("%s.%d.%s", s.natsSubjectPrefix(), s.ShardID, t)
+
+	if s.NatsJetStream != nil {
+		var opts []nats.PubOpt
+		if seq != nil {
+			opts = append(opts, nats.MsgId(fmt.Sprintf("%s-%s-%d", subject, s.sessionID, *seq)))
+		}
+		if _, err := s.NatsJetStream.Publish(subject, data, opts...); err != nil {
+			s.log(LogError, "error publishing %s event to JetStream, %s", t, err)
+		}
+		return
+	}
+
+	if err := s.NATS.Publish(subject, data); err != nil {
+		s.log(LogError, "error publishing %s event to NATS, %s", t, err)
+	}
+}
+
+// QueueSubscribeJetStream subscribes to subject using a durable JetStream
+// pull consumer named after s.NatsQueueName, so that if a worker process
+// dies before acking a message, another worker picks it back up instead of
+// the event being lost. Received messages are unmarshalled and dispatched
+// the same way natsHandler does for ordinary NATS subscriptions.
+func (s *Session) QueueSubscribeJetStream(subject string) (*nats.Subscription, error) {
+	if s.NatsJetStream == nil {
+		return nil, fmt.Errorf("discordgo: NatsJetStream is not configured")
+	}
+
+	sub, err := s.NatsJetStream.PullSubscribe(subject, s.NatsQueueName)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.pullJetStream(sub)
+
+	return sub, nil
+}
+
+// pullJetStream repeatedly fetches and acks messages from a durable
+// JetStream pull consumer, dispatching each one through natsHandler. An
+// empty fetch (nats.ErrTimeout) is the normal idle case and is retried
+// immediately; any other error is logged and backed off exponentially, up
+// to maxPullJetStreamBackoff, so a persistently broken consumer (NATS down,
+// subscription invalidated) doesn't spin the goroutine in a tight loop.
+func (s *Session) pullJetStream(sub *nats.Subscription) {
+	const maxPullJetStreamBackoff = 30 * time.Second
+	backoff := time.Second
+
+	for {
+		msgs, err := sub.Fetch(1)
+		if err != nil {
+			switch err {
+			case nats.ErrConnectionClosed:
+				return
+			case nats.ErrTimeout:
+				// No messages waiting right now; just poll again.
+			default:
+				s.log(LogError, "error fetching from JetStream pull consumer, %s", err)
+				time.Sleep(backoff)
+				backoff *= 2
+				if backoff > maxPullJetStreamBackoff {
+					backoff = maxPullJetStreamBackoff
+				}
+			}
+			continue
+		}
+		backoff = time.Second
+
+		for _, m := range msgs {
+			s.natsHandler(m)
+			m.Ack()
+		}
+	}
 }
 
 // setGuildIds will set the GuildID on all the members of a guild.
@@ -287,8 +649,15 @@ func (s *Session) onInterface(i interface{}) {
 		s.onReady(t)
 	case *GuildCreate:
 		setGuildIds(t.Guild)
+		s.handleGuildCreate(t)
 	case *GuildUpdate:
 		setGuildIds(t.Guild)
+	case *GuildDelete:
+		if t.Unavailable {
+			s.handle(guildUnavailableEventType, &GuildUnavailable{t.Guild})
+		} else {
+			s.handle(guildLeaveEventType, &GuildLeave{t.Guild})
+		}
 	case *VoiceServerUpdate:
 		go s.onVoiceServerUpdate(t)
 	case *VoiceStateUpdate:
@@ -305,9 +674,36 @@ func (s *Session) onInterface(i interface{}) {
 	}
 }
 
+// handleGuildCreate synthesizes the appropriate GuildReady, GuildAvailable,
+// or GuildJoin event for an incoming GuildCreate. It runs before
+// s.State.OnInterface mutates the state, so looking the guild up in
+// s.State here still reflects how things looked before this event applied.
+func (s *Session) handleGuildCreate(gc *GuildCreate) {
+	existing, err := s.State.Guild(gc.ID)
+
+	switch {
+	case err != nil || existing == nil:
+		// The bot has never seen this guild before.
+		s.handle(guildJoinEventType, &GuildJoin{gc.Guild})
+	case s.guildsAwaitingReady > 0:
+		// Still working through the guilds named in the initial READY payload.
+		s.guildsAwaitingReady--
+		s.handle(guildReadyEventType, &GuildReady{gc.Guild})
+	case existing.Unavailable:
+		s.handle(guildAvailableEventType, &GuildAvailable{gc.Guild})
+	default:
+		s.handle(guildJoinEventType, &GuildJoin{gc.Guild})
+	}
+}
+
 // onReady handles the ready event.
 func (s *Session) onReady(r *Ready) {
 
 	// Store the SessionID within the Session struct.
 	s.sessionID = r.SessionID
+
+	// Every guild named here will arrive as its own GuildCreate; track how
+	// many are still outstanding so handleGuildCreate knows when we've left
+	// the initial READY sequence.
+	s.guildsAwaitingReady = len(r.Guilds)
 }
@@ -0,0 +1,183 @@
+package discordgo
+
+import (
+	"sync"
+	"testing"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+func TestEventTypeFromNatsSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"GUILD_CREATE", "GUILD_CREATE"},
+		{"__GUILD_JOIN__", "__GUILD_JOIN__"},
+		{"discord.0.GUILD_CREATE", "GUILD_CREATE"},
+		{"discord.3.__GUILD_JOIN__", "__GUILD_JOIN__"},
+	}
+
+	for _, tt := range tests {
+		if got := eventTypeFromNatsSubject(tt.subject); got != tt.want {
+			t.Errorf("eventTypeFromNatsSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+		}
+	}
+}
+
+// testNatsPayload and testNatsProvider stand in for a generated event type
+// and its EventInterfaceProvider, purely so natsHandler has something
+// registered to look up.
+type testNatsPayload struct {
+	Foo string `json:"foo"`
+}
+
+type testNatsProvider struct{}
+
+func (testNatsProvider) Type() string     { return "TEST_NATS_EVENT" }
+func (testNatsProvider) New() interface{} { return &testNatsPayload{} }
+
+// testEventHandler is a minimal EventHandler, used where a handler needs to
+// be registered without going through handlerForInterface's reflection.
+type testEventHandler struct {
+	t  string
+	fn func(*Session, interface{})
+}
+
+func (h testEventHandler) Type() string                     { return h.t }
+func (h testEventHandler) Handle(s *Session, i interface{}) { h.fn(s, i) }
+
+// TestNatsHandlerStripsSubjectPrefix guards against the subject/provider
+// mismatch that made QueueSubscribeJetStream silently drop every event: a
+// message published under the prefixed NatsMode 2 subject
+// ("discord.<shard>.<type>") must still resolve to the provider registered
+// under the bare type string and reach the handler for that type.
+func TestNatsHandlerStripsSubjectPrefix(t *testing.T) {
+	registerInterfaceProvider(testNatsProvider{})
+
+	s := &Session{SyncEvents: true}
+	var got *testNatsPayload
+	s.addEventHandler(testEventHandler{"TEST_NATS_EVENT", func(_ *Session, i interface{}) {
+		got = i.(*testNatsPayload)
+	}}, false)
+
+	s.natsHandler(&nats.Msg{
+		Subject: "discord.0.TEST_NATS_EVENT",
+		Data:    []byte(`{"foo":"bar"}`),
+	})
+
+	if got == nil {
+		t.Fatal("handler for TEST_NATS_EVENT was never invoked")
+	}
+	if got.Foo != "bar" {
+		t.Fatalf("got Foo=%q, want %q", got.Foo, "bar")
+	}
+}
+
+func TestRemoveEventHandlerInstance(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s *Session)
+	}{
+		{
+			name: "removes a permanent handler",
+			run: func(t *testing.T, s *Session) {
+				var called bool
+				remove := s.AddHandler(func(s *Session, r *Ready) { called = true })
+				remove()
+
+				s.handle("READY", &Ready{})
+				if called {
+					t.Fatalf("handler was called after being removed")
+				}
+			},
+		},
+		{
+			name: "removes a once-handler before it fires",
+			run: func(t *testing.T, s *Session) {
+				var called bool
+				remove := s.AddHandlerOnce(func(s *Session, r *Ready) { called = true })
+				remove()
+
+				s.handle("READY", &Ready{})
+				if called {
+					t.Fatalf("once-handler was called after being removed")
+				}
+			},
+		},
+		{
+			name: "removing during dispatch does not affect the in-flight event",
+			run: func(t *testing.T, s *Session) {
+				var remove func()
+				fired := 0
+				remove = s.AddHandler(func(s *Session, r *Ready) {
+					fired++
+					remove()
+				})
+
+				s.handle("READY", &Ready{})
+				if fired != 1 {
+					t.Fatalf("expected handler to fire once during dispatch, fired %d times", fired)
+				}
+
+				s.handle("READY", &Ready{})
+				if fired != 1 {
+					t.Fatalf("handler fired again after removing itself, fired %d times", fired)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{SyncEvents: true}
+			tt.run(t, s)
+		})
+	}
+}
+
+// TestSyncHandlerSelfRemovalThroughHandleEvent guards against the deadlock
+// that used to hang here: handleEvent previously held s.handlersMu for read
+// across the whole dispatch, so a synchronous handler calling its own
+// remove func (which needs the write lock) from inside Handle would block
+// forever. handle() and Handler.dispatch() now release the lock before
+// invoking anything, so this must complete without hanging.
+func TestSyncHandlerSelfRemovalThroughHandleEvent(t *testing.T) {
+	s := &Session{SyncEvents: true}
+
+	var remove func()
+	fired := 0
+	remove = s.AddSyncHandler(func(s *Session, r *Ready) {
+		fired++
+		remove()
+	})
+
+	s.handleEvent("READY", &Ready{})
+	if fired != 1 {
+		t.Fatalf("expected handler to fire once, fired %d times", fired)
+	}
+
+	s.handleEvent("READY", &Ready{})
+	if fired != 1 {
+		t.Fatalf("handler fired again after removing itself, fired %d times", fired)
+	}
+}
+
+func TestRemoveEventHandlerInstance_ConcurrentAddRemove(t *testing.T) {
+	s := &Session{SyncEvents: true}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remove := s.AddHandler(func(s *Session, r *Ready) {})
+			remove()
+		}()
+	}
+	wg.Wait()
+
+	if n := len(s.handlers["READY"]); n != 0 {
+		t.Fatalf("expected all handlers to be removed, got %d remaining", n)
+	}
+}
@@ -0,0 +1,75 @@
+package discordgo
+
+// Synthetic guild lifecycle events.
+//
+// A raw GuildCreate fires for three very different situations: a guild
+// streaming in as part of the initial READY payload, a previously
+// unavailable guild coming back online, and the bot being added to a brand
+// new guild. GuildDelete is similarly overloaded between an outage and an
+// actual removal. The events below let handlers subscribe to the specific
+// case they care about instead of re-deriving it from Session.State on
+// every GuildCreate/GuildDelete. The raw events still fire alongside these.
+
+// GuildReady is a synthetic event fired for each guild that arrives while
+// the session is still working through the initial READY payload.
+type GuildReady struct {
+	*Guild
+}
+
+// GuildAvailable is a synthetic event fired when a guild that was
+// previously marked Unavailable comes back online.
+type GuildAvailable struct {
+	*Guild
+}
+
+// GuildJoin is a synthetic event fired for a GuildCreate of a guild the bot
+// didn't already know about (even during the initial READY sequence), and
+// for any other GuildCreate outside of that sequence whose guild was not
+// marked Unavailable.
+type GuildJoin struct {
+	*Guild
+}
+
+// GuildUnavailable is a synthetic event fired when a GuildDelete arrives
+// with Unavailable set, meaning the guild is experiencing an outage rather
+// than having actually removed the bot.
+type GuildUnavailable struct {
+	*Guild
+}
+
+// GuildLeave is a synthetic event fired when the bot is removed from a
+// guild, or the guild is deleted.
+type GuildLeave struct {
+	*Guild
+}
+
+const (
+	guildReadyEventType       = "__GUILD_READY__"
+	guildAvailableEventType   = "__GUILD_AVAILABLE__"
+	guildJoinEventType        = "__GUILD_JOIN__"
+	guildUnavailableEventType = "__GUILD_UNAVAILABLE__"
+	guildLeaveEventType       = "__GUILD_LEAVE__"
+)
+
+// guildLifecycleEventProvider is an EventInterfaceProvider for one of the
+// synthetic guild lifecycle events above.
+type guildLifecycleEventProvider struct {
+	t  string
+	nw func() interface{}
+}
+
+func (p guildLifecycleEventProvider) Type() string {
+	return p.t
+}
+
+func (p guildLifecycleEventProvider) New() interface{} {
+	return p.nw()
+}
+
+func init() {
+	registerInterfaceProvider(guildLifecycleEventProvider{guildReadyEventType, func() interface{} { return &GuildReady{} }})
+	registerInterfaceProvider(guildLifecycleEventProvider{guildAvailableEventType, func() interface{} { return &GuildAvailable{} }})
+	registerInterfaceProvider(guildLifecycleEventProvider{guildJoinEventType, func() interface{} { return &GuildJoin{} }})
+	registerInterfaceProvider(guildLifecycleEventProvider{guildUnavailableEventType, func() interface{} { return &GuildUnavailable{} }})
+	registerInterfaceProvider(guildLifecycleEventProvider{guildLeaveEventType, func() interface{} { return &GuildLeave{} }})
+}
@@ -2,7 +2,6 @@ package discordgo
 
 import (
 	"fmt"
-	"math"
 	"sort"
 	"time"
 )
@@ -198,35 +197,56 @@ func (r *Role) Move(position int) (err error) {
 		return ErrUnmovableDefaultRole
 	}
 
-	var editedRoles Roles
-	var edits []*RoleMove
-	min := int(math.Min(float64(position), float64(r.Position)))
-	max := int(math.Max(float64(position), float64(r.Position)))
+	edits := rolesMoveEdits(r.Guild.Roles, r.ID, position)
+
+	_, err = r.Session.GuildRoleReorder(r.Guild.ID, edits)
+	return
+}
 
-	for _, role := range r.Guild.Roles {
-		if role.ID != r.ID && role.Position <= max && role.Position >= min {
-			editedRoles = append(editedRoles, role)
+// rolesMoveEdits computes the RoleMove payload needed to move the role with
+// the given ID to position within roles, re-numbering positions from the
+// bottom up. Position 0 is reserved for @everyone, so position is clamped
+// to [1, len(roles)-1]. Only roles whose position actually changes are
+// included in the result.
+func rolesMoveEdits(roles Roles, roleID string, position int) []*RoleMove {
+	sorted := make(Roles, len(roles))
+	copy(sorted, roles)
+	SortRolesByPosition(sorted)
+
+	var moved *Role
+	without := make(Roles, 0, len(sorted))
+	for _, role := range sorted {
+		if role.ID == roleID {
+			moved = role
+			continue
 		}
+		without = append(without, role)
 	}
-
-	sort.Sort(sort.Reverse(editedRoles))
-
-	if position == min {
-		editedRoles = append(Roles{r}, editedRoles...)
-	} else {
-		editedRoles = append(editedRoles, r)
+	if moved == nil {
+		return nil
 	}
 
-	for p, i := min, 0; p <= max+1 && i < len(editedRoles); p, i = p+1, i+1 {
-		editedRoles[i].Position = p
-		edits = append(edits, &RoleMove{editedRoles[i].ID, editedRoles[i].Position})
+	index := position
+	if index < 1 {
+		index = 1
+	}
+	if index > len(without) {
+		index = len(without)
 	}
 
-	_, err = r.Session.GuildRoleReorder(r.Guild.ID, edits)
-	if err != nil {
-		return
+	reordered := make(Roles, 0, len(without)+1)
+	reordered = append(reordered, without[:index]...)
+	reordered = append(reordered, moved)
+	reordered = append(reordered, without[index:]...)
+
+	var edits []*RoleMove
+	for i, role := range reordered {
+		if role.Position != i {
+			role.Position = i
+			edits = append(edits, &RoleMove{role.ID, role.Position})
+		}
 	}
-	return
+	return edits
 }
 
 // Delete deletes the role
@@ -249,6 +269,18 @@ func (r Roles) Swap(i, j int) {
 	r[i], r[j] = r[j], r[i]
 }
 
+// SortRolesByPosition sorts roles in ascending order of Position, breaking
+// ties on role ID (stably) to match Discord's own ordering semantics for
+// roles that share a position.
+func SortRolesByPosition(roles Roles) {
+	sort.SliceStable(roles, func(i, j int) bool {
+		if roles[i].Position != roles[j].Position {
+			return roles[i].Position < roles[j].Position
+		}
+		return roles[i].ID < roles[j].ID
+	})
+}
+
 // ContainsID checks if the slice of Role objects contains a role with the given ID
 // ID     : the ID to search for
 func (r Roles) ContainsID(ID string) bool {
@@ -0,0 +1,91 @@
+package discordgo
+
+import "testing"
+
+func TestSortRolesByPosition(t *testing.T) {
+	roles := Roles{
+		{ID: "3", Position: 2},
+		{ID: "1", Position: 0},
+		{ID: "2b", Position: 1},
+		{ID: "2a", Position: 1},
+	}
+
+	SortRolesByPosition(roles)
+
+	want := []string{"1", "2a", "2b", "3"}
+	for i, id := range want {
+		if roles[i].ID != id {
+			t.Fatalf("position %d: got role %s, want %s", i, roles[i].ID, id)
+		}
+	}
+}
+
+func TestRolesMoveEdits(t *testing.T) {
+	// everyone(0) < mod(1) < vip(2) < admin(3)
+	guildRoles := func() Roles {
+		return Roles{
+			{ID: "everyone", Position: 0},
+			{ID: "mod", Position: 1},
+			{ID: "vip", Position: 2},
+			{ID: "admin", Position: 3},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		roleID   string
+		position int
+		want     []*RoleMove
+	}{
+		{
+			name:     "move up",
+			roleID:   "mod",
+			position: 2,
+			want: []*RoleMove{
+				{ID: "vip", Position: 1},
+				{ID: "mod", Position: 2},
+			},
+		},
+		{
+			name:     "move down",
+			roleID:   "admin",
+			position: 1,
+			want: []*RoleMove{
+				{ID: "admin", Position: 1},
+				{ID: "mod", Position: 2},
+				{ID: "vip", Position: 3},
+			},
+		},
+		{
+			name:     "move to the top",
+			roleID:   "mod",
+			position: 3,
+			want: []*RoleMove{
+				{ID: "vip", Position: 1},
+				{ID: "admin", Position: 2},
+				{ID: "mod", Position: 3},
+			},
+		},
+		{
+			name:     "no-op position",
+			roleID:   "mod",
+			position: 1,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rolesMoveEdits(guildRoles(), tt.roleID, tt.position)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d edits, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if *got[i] != *tt.want[i] {
+					t.Fatalf("edit %d: got %+v, want %+v", i, *got[i], *tt.want[i])
+				}
+			}
+		})
+	}
+}